@@ -0,0 +1,121 @@
+// Package fsm is a tiny conversation-state engine: each multi-step flow
+// (add a person, edit a payment, ...) is a chain of State values, and a
+// SessionManager tracks which state each user is in, with idle sessions
+// garbage-collected so an abandoned flow doesn't linger forever.
+package fsm
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// Input is what drives a state transition — the text the user sent,
+// whether from a text message or an inline button's callback data.
+type Input struct {
+	UserID   int64
+	Username string
+	Text     string
+}
+
+// Reply is one message a State wants sent back to the user.
+type Reply struct {
+	Text   string
+	Markup *telebot.ReplyMarkup
+	Mode   string // e.g. telebot.ModeHTML; empty for plain text
+}
+
+// State is one step of a conversation. Handle validates in and returns
+// the next state to move to (or nil to end the flow), the replies to
+// send, and an error only for unexpected failures (a validation problem
+// is reported by returning the same state again with an explanatory
+// Reply, not via err).
+type State interface {
+	Handle(in Input) (next State, replies []Reply, err error)
+}
+
+type session struct {
+	state     State
+	updatedAt time.Time
+}
+
+// SessionManager tracks the in-flight state per user, guarded by an
+// RWMutex, and garbage-collects sessions that have been idle past
+// idleTimeout.
+type SessionManager struct {
+	mu          sync.RWMutex
+	sessions    map[int64]*session
+	idleTimeout time.Duration
+}
+
+// NewSessionManager starts a SessionManager whose idle sessions are
+// reaped every idleTimeout/2 (but no more often than once a minute).
+func NewSessionManager(idleTimeout time.Duration) *SessionManager {
+	m := &SessionManager{
+		sessions:    make(map[int64]*session),
+		idleTimeout: idleTimeout,
+	}
+
+	interval := idleTimeout / 2
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	go m.gcLoop(interval)
+
+	return m
+}
+
+// Enter starts userID on state s, replacing any flow already in progress.
+func (m *SessionManager) Enter(userID int64, s State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[userID] = &session{state: s, updatedAt: time.Now()}
+}
+
+// Current returns userID's in-flight state, if any.
+func (m *SessionManager) Current(userID int64) (State, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[userID]
+	if !ok {
+		return nil, false
+	}
+	return s.state, true
+}
+
+// Transition moves userID to next, or ends the flow if next is nil.
+func (m *SessionManager) Transition(userID int64, next State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if next == nil {
+		delete(m.sessions, userID)
+		return
+	}
+	m.sessions[userID] = &session{state: next, updatedAt: time.Now()}
+}
+
+// Cancel ends userID's flow, if any, and reports whether one existed.
+func (m *SessionManager) Cancel(userID int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sessions[userID]; !ok {
+		return false
+	}
+	delete(m.sessions, userID)
+	return true
+}
+
+func (m *SessionManager) gcLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		m.mu.Lock()
+		for id, s := range m.sessions {
+			if now.Sub(s.updatedAt) > m.idleTimeout {
+				delete(m.sessions, id)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
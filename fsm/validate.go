@@ -0,0 +1,34 @@
+package fsm
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrEmpty is returned by NonEmpty for blank input.
+var ErrEmpty = errors.New("input must not be empty")
+
+// ErrNotNumeric is returned by Numeric for input that doesn't parse as a
+// number (so a user typing "abc" for a summa gets a proper error instead
+// of a silent NaN write to Sheets).
+var ErrNotNumeric = errors.New("input must be a number")
+
+// NonEmpty rejects blank (or whitespace-only) input.
+func NonEmpty(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return ErrEmpty
+	}
+	return nil
+}
+
+// Numeric accepts input parseable as a float, tolerating a comma decimal
+// separator and surrounding whitespace the way the rest of the bot does.
+func Numeric(s string) error {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, ",", ".")
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return ErrNotNumeric
+	}
+	return nil
+}
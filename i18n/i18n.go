@@ -0,0 +1,195 @@
+// Package i18n holds the bot's message catalogs and per-user language
+// selection so the same handler code can speak Uzbek, Russian or English.
+package i18n
+
+import "fmt"
+
+// Supported language codes. Uz is the historical default — the bot only
+// ever spoke Uzbek before this package existed.
+const (
+	Uz = "uz"
+	Ru = "ru"
+	En = "en"
+
+	Default = Uz
+)
+
+// Languages lists the supported codes in the order they should be offered
+// to the user (e.g. on the /lang keyboard).
+var Languages = []string{Uz, Ru, En}
+
+// Names gives the human-readable label for each language, in its own
+// language, for use on the /lang keyboard.
+var Names = map[string]string{
+	Uz: "O'zbekcha",
+	Ru: "Русский",
+	En: "English",
+}
+
+// months lists the display month names per language, in the same order as
+// the canonical Uzbek names used as monthsMap keys and column anchors. The column
+// mapping itself is keyed by the Uzbek name and never changes — only the
+// text shown to the user changes.
+var months = map[string][]string{
+	Uz: {"Yanvar", "Fevral", "Mart", "Aprel", "May", "Iyun", "Iyul", "Avgust", "Sentabr", "Oktabr", "Noyabr", "Dekabr"},
+	Ru: {"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"},
+	En: {"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+}
+
+// Months returns the display month names for lang, in the same order as
+// the canonical monthsMap keys in main.go.
+func Months(lang string) []string {
+	if m, ok := months[lang]; ok {
+		return m
+	}
+	return months[Default]
+}
+
+var catalog = map[string]map[string]string{
+	Uz: {
+		"start.welcome":      "👋 Xush kelibsiz!\nQuyidagi buyruqlardan foydalanishingiz mumkin:\n- /list   -- Shaxslar ro'yxatini ko'rish\n- /add    -- Yangi shaxs qo'shish\n- /edit   -- Mavjud shaxs ma'lumotlarini o'zgartirish\n- /totals -- Umumiy natijalarni ko'rish\n- /history -- Shaxs tarixini ko'rish\n- /lang   -- Tilni o'zgartirish",
+		"list.prompt":        "Shaxsni tanlang:",
+		"edit.choose_person": "✏️ O'zgartirish uchun shaxsni tanlang:",
+		"add.prompt_name":    "✏️ Ism kiriting:(Masalan, Abdusattor yoki Sardor)",
+		"add.prompt_phone":   "📱 Telefon kiriting: (Masalan, iPhone 16 Pro Max modelini yozing)",
+		"add.prompt_summa":   "💰 Summani kiriting: (Bu yerga bergan summangizni $ belgisisiz, faqat raqam yozing)",
+		"add.prompt_nominal": "📊 Nominal summani kiriting: (Telefonning haqiqiy narxini $ belgisisiz, faqat raqam yozing)",
+		"add.failed":         "❌ Failed to save: %s",
+		"add.success":        "✅ Qo'shildi: %s, %s, %s, Nominal: %s",
+		"edit.choose_year":   "📅 Select year:",
+		"edit.choose_month":  "📅 Oyni tanlang:",
+		"edit.prompt_summa":  "💰 Enter summa for %s %s (%s):",
+		"edit.invalid_state": "❌ Internal error: invalid state",
+		"edit.failed":        "❌ Failed to update: %s",
+		"edit.success":       "✅ %s-yil %s oyi yangilandi — %s (%s) = %s",
+		"totals.report":      "<pre> 📊 Umumiy natijalar:\n\n💰 Summa: %s\n✅ Berdi: %s\n💸 Qoldiq: %s\n📊 Nominal Sum: %s </pre>",
+		"select.invalid":     "❌ Invalid selection",
+		"select.not_found":   "Not found.",
+		"select.header":      "📌 Name: %v\n📱 Phone: %v\n💰 Summa: %v\n✅ Berdi: %v\n💸 Qoldiq: %v\n📊 Nominal Sum: %v\n🤑 Foyda: %v\n\n",
+		"select.payments":    "📅 Payments:\n<pre>",
+		"export.button":      "📥 Yuklab olish",
+		"export.failed":      "❌ Eksport qilishda xatolik: %s",
+		"history.usage":      "✏️ Foydalanish: /history <ism>",
+		"history.failed":     "❌ Tarixni olishda xatolik: %s",
+		"history.not_found":  "Bu shaxs uchun tarix topilmadi.",
+		"history.header":     "🕘 %s uchun tarix:\n<pre>",
+		"history.entry":      "%s | %s (%d) | %s %s: %s → %s\n",
+		"auth.denied":        "❌ You are not allowed to use this bot.",
+		"lang.prompt":        "🌐 Tilni tanlang:",
+		"lang.set":           "✅ Til o'zgartirildi: %s",
+		"cancel.success":     "✅ Amal bekor qilindi.",
+		"cancel.none":        "Hozircha bekor qilinadigan amal yo'q.",
+		"validate.empty":     "❌ Bo'sh bo'lishi mumkin emas, qayta urinib ko'ring:",
+		"validate.numeric":   "❌ Faqat raqam kiriting, qayta urinib ko'ring:",
+		"grant.usage":        "✏️ Foydalanish: /grant <foydalanuvchi> <viewer|editor|admin>",
+		"grant.invalid_role": "❌ Noma'lum rol. Mumkin bo'lganlar: viewer, editor, admin",
+		"grant.failed":       "❌ Rol berishda xatolik: %s",
+		"grant.success":      "✅ %s uchun %s roli berildi",
+		"revoke.usage":       "✏️ Foydalanish: /revoke <foydalanuvchi>",
+		"revoke.failed":      "❌ Rolni bekor qilishda xatolik: %s",
+		"revoke.success":     "✅ %s uchun rol bekor qilindi",
+	},
+	Ru: {
+		"start.welcome":      "👋 Добро пожаловать!\nДоступные команды:\n- /list   -- Список людей\n- /add    -- Добавить человека\n- /edit   -- Изменить данные\n- /totals -- Общий итог\n- /history -- История изменений\n- /lang   -- Сменить язык",
+		"list.prompt":        "Выберите человека:",
+		"edit.choose_person": "✏️ Выберите человека для изменения:",
+		"add.prompt_name":    "✏️ Введите имя: (например, Abdusattor или Sardor)",
+		"add.prompt_phone":   "📱 Введите телефон: (например, модель iPhone 16 Pro Max)",
+		"add.prompt_summa":   "💰 Введите сумму: (без знака $, только число)",
+		"add.prompt_nominal": "📊 Введите номинальную сумму: (реальная цена телефона без $, только число)",
+		"add.failed":         "❌ Не удалось сохранить: %s",
+		"add.success":        "✅ Добавлено: %s, %s, %s, Номинал: %s",
+		"edit.choose_year":   "📅 Выберите год:",
+		"edit.choose_month":  "📅 Выберите месяц:",
+		"edit.prompt_summa":  "💰 Введите сумму за %s %s (%s):",
+		"edit.invalid_state": "❌ Внутренняя ошибка: неверное состояние",
+		"edit.failed":        "❌ Не удалось обновить: %s",
+		"edit.success":       "✅ %s год, %s обновлён — %s (%s) = %s",
+		"totals.report":      "<pre> 📊 Общие итоги:\n\n💰 Сумма: %s\n✅ Оплачено: %s\n💸 Остаток: %s\n📊 Номинал: %s </pre>",
+		"select.invalid":     "❌ Неверный выбор",
+		"select.not_found":   "Не найдено.",
+		"select.header":      "📌 Имя: %v\n📱 Телефон: %v\n💰 Сумма: %v\n✅ Оплачено: %v\n💸 Остаток: %v\n📊 Номинал: %v\n🤑 Прибыль: %v\n\n",
+		"select.payments":    "📅 Платежи:\n<pre>",
+		"export.button":      "📥 Экспорт",
+		"export.failed":      "❌ Не удалось экспортировать: %s",
+		"history.usage":      "✏️ Использование: /history <имя>",
+		"history.failed":     "❌ Не удалось получить историю: %s",
+		"history.not_found":  "История для этого человека не найдена.",
+		"history.header":     "🕘 История для %s:\n<pre>",
+		"history.entry":      "%s | %s (%d) | %s %s: %s → %s\n",
+		"auth.denied":        "❌ Вам не разрешено пользоваться этим ботом.",
+		"lang.prompt":        "🌐 Выберите язык:",
+		"lang.set":           "✅ Язык изменён: %s",
+		"cancel.success":     "✅ Действие отменено.",
+		"cancel.none":        "Сейчас нечего отменять.",
+		"validate.empty":     "❌ Не может быть пустым, попробуйте снова:",
+		"validate.numeric":   "❌ Введите только число, попробуйте снова:",
+		"grant.usage":        "✏️ Использование: /grant <пользователь> <viewer|editor|admin>",
+		"grant.invalid_role": "❌ Неизвестная роль. Доступны: viewer, editor, admin",
+		"grant.failed":       "❌ Не удалось назначить роль: %s",
+		"grant.success":      "✅ %s получил роль %s",
+		"revoke.usage":       "✏️ Использование: /revoke <пользователь>",
+		"revoke.failed":      "❌ Не удалось отозвать роль: %s",
+		"revoke.success":     "✅ Роль для %s отозвана",
+	},
+	En: {
+		"start.welcome":      "👋 Welcome!\nAvailable commands:\n- /list   -- View the list of people\n- /add    -- Add a new person\n- /edit   -- Edit an existing person's data\n- /totals -- View overall totals\n- /history -- View a person's change history\n- /lang   -- Change language",
+		"list.prompt":        "Choose a person:",
+		"edit.choose_person": "✏️ Choose a person to edit:",
+		"add.prompt_name":    "✏️ Enter the name: (e.g. Abdusattor or Sardor)",
+		"add.prompt_phone":   "📱 Enter the phone: (e.g. type the iPhone 16 Pro Max model)",
+		"add.prompt_summa":   "💰 Enter the amount: (no $ sign, digits only)",
+		"add.prompt_nominal": "📊 Enter the nominal amount: (the phone's real price, no $ sign, digits only)",
+		"add.failed":         "❌ Failed to save: %s",
+		"add.success":        "✅ Added: %s, %s, %s, Nominal: %s",
+		"edit.choose_year":   "📅 Select year:",
+		"edit.choose_month":  "📅 Select month:",
+		"edit.prompt_summa":  "💰 Enter summa for %s %s (%s):",
+		"edit.invalid_state": "❌ Internal error: invalid state",
+		"edit.failed":        "❌ Failed to update: %s",
+		"edit.success":       "✅ %s %s updated — %s (%s) = %s",
+		"totals.report":      "<pre> 📊 Overall totals:\n\n💰 Summa: %s\n✅ Paid: %s\n💸 Remaining: %s\n📊 Nominal Sum: %s </pre>",
+		"select.invalid":     "❌ Invalid selection",
+		"select.not_found":   "Not found.",
+		"select.header":      "📌 Name: %v\n📱 Phone: %v\n💰 Summa: %v\n✅ Paid: %v\n💸 Remaining: %v\n📊 Nominal Sum: %v\n🤑 Profit: %v\n\n",
+		"select.payments":    "📅 Payments:\n<pre>",
+		"export.button":      "📥 Export",
+		"export.failed":      "❌ Failed to export: %s",
+		"history.usage":      "✏️ Usage: /history <name>",
+		"history.failed":     "❌ Failed to fetch history: %s",
+		"history.not_found":  "No history found for this person.",
+		"history.header":     "🕘 History for %s:\n<pre>",
+		"history.entry":      "%s | %s (%d) | %s %s: %s → %s\n",
+		"auth.denied":        "❌ You are not allowed to use this bot.",
+		"lang.prompt":        "🌐 Choose a language:",
+		"lang.set":           "✅ Language set to: %s",
+		"cancel.success":     "✅ Cancelled.",
+		"cancel.none":        "There's nothing to cancel right now.",
+		"validate.empty":     "❌ Must not be empty, try again:",
+		"validate.numeric":   "❌ Please enter a number, try again:",
+		"grant.usage":        "✏️ Usage: /grant <user> <viewer|editor|admin>",
+		"grant.invalid_role": "❌ Unknown role. Valid roles: viewer, editor, admin",
+		"grant.failed":       "❌ Failed to grant role: %s",
+		"grant.success":      "✅ Granted %s the %s role",
+		"revoke.usage":       "✏️ Usage: /revoke <user>",
+		"revoke.failed":      "❌ Failed to revoke role: %s",
+		"revoke.success":     "✅ Revoked %s's role",
+	},
+}
+
+// T renders message id in lang, falling back to Default if lang or id is
+// unknown. Extra args are applied via fmt.Sprintf.
+func T(lang, id string, args ...any) string {
+	msgs, ok := catalog[lang]
+	if !ok {
+		msgs = catalog[Default]
+	}
+	msg, ok := msgs[id]
+	if !ok {
+		msg = catalog[Default][id]
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
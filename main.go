@@ -5,14 +5,17 @@ import (
 	"fmt"
 	"html"
 	"log"
+	"log/slog"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 	"unicode"
 
 	"github.com/caarlos0/env/v11"
+	goerrors "github.com/go-errors/errors"
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
 	"golang.org/x/oauth2/google"
@@ -20,41 +23,35 @@ import (
 	"google.golang.org/api/sheets/v4"
 
 	"gopkg.in/telebot.v3"
+
+	"github.com/Hot-One/variant-tg-bot/fsm"
+	"github.com/Hot-One/variant-tg-bot/i18n"
+	"github.com/Hot-One/variant-tg-bot/report"
+	"github.com/Hot-One/variant-tg-bot/storage"
 )
 
 const (
 	readRange = "Лист1!A2:AZ"
 	sheetName = "Лист1"
+
+	refreshTTL  = time.Minute
+	dbPath      = "bot.db"
+	idleTimeout = 10 * time.Minute
 )
 
 var spreadsheetID = ""
 
-type userState struct {
-	Step       int
-	Name       string
-	Phone      string
-	Summa      string
-	NominalSum string
-}
-
-type editState struct {
-	Step  int
-	Name  string // will contain "Name|Phone"
-	Year  string
-	Month string
-}
-
-type Storage struct {
-	Data [][]any
-	mu   sync.Mutex
-}
-
 var (
-	userStates   = make(map[int64]*userState)
-	editStates   = make(map[int64]*editState)
-	allowedUsers = map[string]bool{}
+	ownerUser string
+
+	srv        *sheets.Service
+	strg       *storage.Store
+	sessions   *fsm.SessionManager
+	yearLayout = defaultYearLayout
 
-	srv *sheets.Service
+	// logger emits structured JSON so updates and panics can be traced in
+	// aggregate, instead of grepping plain-text log.Println output.
+	logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
 )
 
 var monthsMap = map[string]int{
@@ -72,17 +69,85 @@ var monthsMap = map[string]int{
 	"Dekabr":  11,
 }
 
+// canonicalMonths lists monthsMap's keys in column order. It is the stable
+// identifier used in callback data and sheet math; i18n.Months(lang) gives
+// the text shown to the user for the same positions.
+var canonicalMonths = []string{
+	"Yanvar", "Fevral", "Mart", "Aprel", "May", "Iyun",
+	"Iyul", "Avgust", "Sentabr", "Oktabr", "Noyabr", "Dekabr",
+}
+
+// YearLayout maps a year (e.g. "2025") to the 1-based column where that
+// year's "Yanvar" payment lives (so G -> 7). It's either discovered from
+// the sheet's header row or, failing that, falls back to the historical
+// hard-coded layout.
+type YearLayout map[string]int
+
+var defaultYearLayout = YearLayout{"2025": 7, "2026": 19}
+
+// yearPattern matches a bare 4-digit year such as the "2025"/"2026" labels
+// telephone-book-style sheets put above each year's month block.
+var yearPattern = regexp.MustCompile(`^(19|20)\d{2}$`)
+
+// discoverYearLayout scans a header row (same column alignment as the data
+// rows, i.e. header[0] is column A) for year labels and records the column
+// each one starts at. It returns defaultYearLayout if none are found.
+func discoverYearLayout(header []any) YearLayout {
+	layout := YearLayout{}
+	for col, cell := range header {
+		s := strings.TrimSpace(fmt.Sprintf("%v", cell))
+		if yearPattern.MatchString(s) {
+			layout[s] = col + 1
+		}
+	}
+	if len(layout) == 0 {
+		return defaultYearLayout
+	}
+	return layout
+}
+
+// sortedYears returns the layout's years in ascending order, for stable
+// button ordering.
+func (l YearLayout) sortedYears() []string {
+	years := make([]string, 0, len(l))
+	for y := range l {
+		years = append(years, y)
+	}
+	sort.Strings(years)
+	return years
+}
+
+// colIndexToA1 converts a 1-based column index into spreadsheet column
+// notation (1 -> A, 26 -> Z, 27 -> AA, ...).
+func colIndexToA1(col int) string {
+	var buf []byte
+	for col > 0 {
+		col--
+		buf = append(buf, byte('A'+col%26))
+		col /= 26
+	}
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+	return string(buf)
+}
+
 type Config struct {
 	SpreadsheetID string   `env:"spreadsheetID"`
 	TelegramToken string   `env:"telegramToken"`
 	AllowedUsers  []string `env:"allowedUsers" envSeparator:","`
+
+	OwnerUser   string   `env:"OWNER_USER"`
+	AdminUsers  []string `env:"ADMIN_USERS" envSeparator:","`
+	EditorUsers []string `env:"EDITOR_USERS" envSeparator:","`
+	ViewerUsers []string `env:"VIEWER_USERS" envSeparator:","`
 }
 
 func main() {
 	var cfg Config
 
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+		logger.Warn("no .env file found")
 	}
 
 	if err := env.Parse(&cfg); err != nil {
@@ -100,20 +165,21 @@ func main() {
 		log.Fatal("telegramToken is required")
 	}
 
-	for _, u := range cfg.AllowedUsers {
-		allowedUsers[strings.ToLower(u)] = true
+	ownerUser = strings.ToLower(cfg.OwnerUser)
+
+	var err error
+	strg, err = storage.Open(dbPath, refreshTTL)
+	if err != nil {
+		log.Fatalf("Unable to open storage: %v", err)
 	}
+	defer strg.Close()
 
-	var (
-		strg = &Storage{
-			Data: [][]any{},
-			mu:   sync.Mutex{},
-		}
-		pref = telebot.Settings{
-			Token:  cfg.TelegramToken,
-			Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
-		}
-	)
+	seedACL(strg, cfg)
+
+	pref := telebot.Settings{
+		Token:  cfg.TelegramToken,
+		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
+	}
 
 	bot, err := telebot.NewBot(pref)
 	if err != nil {
@@ -139,30 +205,41 @@ func main() {
 		log.Fatalf("Unable to retrieve Sheets client: %v", err)
 	}
 
-	refreshData(strg)
+	if header, err := srv.Spreadsheets.Values.Get(spreadsheetID, sheetName+"!A1:AZ1").Do(); err != nil {
+		logger.Warn("unable to read header row, falling back to default year layout", "error", err)
+	} else if len(header.Values) > 0 {
+		yearLayout = discoverYearLayout(header.Values[0])
+	}
+
+	refreshData(strg, false)
+
+	sessions = fsm.NewSessionManager(idleTimeout)
 
-	bot.Use(authMiddleware)
+	bot.Use(recoverMiddleware, logMiddleware, authMiddleware, cancelMiddleware)
 
 	// Handle /start command
 	bot.Handle("/start", func(c telebot.Context) error {
-		msg := `👋 Xush kelibsiz!
-				Quyidagi buyruqlardan foydalanishingiz mumkin:
-				- /list   -- Shaxslar ro'yxatini ko'rish
-				- /add    -- Yangi shaxs qo'shish
-				- /edit   -- Mavjud shaxs ma'lumotlarini o'zgartirish
-				- /totals -- Umumiy natijalarni ko'rish
-		`
-
-		return c.Send(msg)
+		return c.Send(t(c.Sender().ID, "start.welcome"))
+	})
+
+	// Handle /lang command
+	bot.Handle("/lang", func(c telebot.Context) error {
+		menu := &telebot.ReplyMarkup{}
+		var buttons []telebot.Btn
+		for _, lang := range i18n.Languages {
+			buttons = append(buttons, menu.Data(i18n.Names[lang], "set_lang", lang))
+		}
+		menu.Inline(menu.Row(buttons...))
+		return c.Send(t(c.Sender().ID, "lang.prompt"), menu)
 	})
 
 	// Handle /list command
 	bot.Handle("/list", func(c telebot.Context) error {
-		refreshData(strg)
+		refreshData(strg, false)
 		menu := &telebot.ReplyMarkup{}
 		var rows []telebot.Row
 
-		for indx, row := range strg.Data {
+		for indx, row := range strg.Rows() {
 			if indx == 0 {
 				continue // skip header
 			}
@@ -178,21 +255,21 @@ func main() {
 		}
 
 		menu.Inline(rows...)
-		return c.Send("Shaxsni tanlang:", menu)
+		return c.Send(t(c.Sender().ID, "list.prompt"), menu)
 	})
 
 	// Handle /add command
 	bot.Handle("/add", func(c telebot.Context) error {
-		userStates[c.Sender().ID] = &userState{Step: 1}
-		return c.Send("✏️ Ism kiriting:(Masalan, Abdusattor yoki Sardor)")
-	})
+		sessions.Enter(c.Sender().ID, &addNameState{})
+		return c.Send(t(c.Sender().ID, "add.prompt_name"))
+	}, RequireRole(storage.RoleEditor))
 
 	// Handle /edit command
 	bot.Handle("/edit", func(c telebot.Context) error {
-		refreshData(strg)
+		refreshData(strg, false)
 		menu := &telebot.ReplyMarkup{}
 		var buttons []telebot.Btn
-		for _, row := range strg.Data {
+		for _, row := range strg.Rows() {
 			if len(row) > 1 {
 				name := fmt.Sprintf("%v", row[0])
 				phone := fmt.Sprintf("%v", row[1])
@@ -202,12 +279,12 @@ func main() {
 			}
 		}
 		menu.Inline(buttons)
-		return c.Send("✏️ O'zgartirish uchun shaxsni tanlang:", menu)
-	})
+		return c.Send(t(c.Sender().ID, "edit.choose_person"), menu)
+	}, RequireRole(storage.RoleEditor))
 
 	// Handle /totals command
 	bot.Handle("/totals", func(c telebot.Context) error {
-		refreshData(strg)
+		refreshData(strg, false)
 
 		var (
 			totalSumma      float64
@@ -216,7 +293,7 @@ func main() {
 			totalNominalSum float64
 		)
 
-		for _, row := range strg.Data {
+		for _, row := range strg.Rows() {
 			if len(row) < 6 {
 				continue
 			}
@@ -227,239 +304,537 @@ func main() {
 			totalNominalSum += parseFloat(row[5]) // F (Nominal Sum)
 		}
 
-		msg := fmt.Sprintf(
-			"<pre> 📊 Umumiy natijalar:\n\n💰 Summa: %s\n✅ Berdi: %s\n💸 Qoldiq: %s\n📊 Nominal Sum: %s </pre>",
+		msg := t(c.Sender().ID, "totals.report",
 			formatMoney(totalSumma),
 			formatMoney(totalBerdi),
 			formatMoney(totalQoldiq),
 			formatMoney(totalNominalSum),
 		)
 
-		return c.Send(msg, telebot.ModeHTML)
+		menu := &telebot.ReplyMarkup{}
+		menu.Inline(menu.Row(menu.Data(t(c.Sender().ID, "export.button"), "export_totals", "")))
+
+		return c.Send(msg, telebot.ModeHTML, menu)
 	})
 
-	// Handle add + edit text flow
-	bot.Handle(telebot.OnText, func(c telebot.Context) error {
-		// ADD FLOW
-		if state, ok := userStates[c.Sender().ID]; ok {
-			switch state.Step {
-			case 1:
-				state.Name = c.Text()
-				state.Step = 2
-				return c.Send("📱 Telefon kiriting: (Masalan, iPhone 16 Pro Max modelini yozing)")
-			case 2:
-				state.Phone = formatPhoneModel(c.Text())
-				state.Step = 3
-				return c.Send("💰 Summani kiriting: (Bu yerga bergan summangizni $ belgisisiz, faqat raqam yozing)")
-			case 3:
-				state.Summa = c.Text()
-				state.Step = 4
-				return c.Send("📊 Nominal summani kiriting: (Telefonning haqiqiy narxini $ belgisisiz, faqat raqam yozing)")
-			case 4:
-				state.NominalSum = c.Text()
-
-				rowIndex := len(strg.Data) + 2
-				row := []any{
-					state.Name,
-					state.Phone,
-					state.Summa,
-					fmt.Sprintf("=СУММ(G%d:AZ%d)", rowIndex, rowIndex),
-					fmt.Sprintf("=C%d-D%d", rowIndex, rowIndex),
-					state.NominalSum,
-				}
-
-				vr := &sheets.ValueRange{Values: [][]any{row}}
-				_, err := srv.Spreadsheets.
-					Values.
-					Append(spreadsheetID, sheetName+"!A3", vr).
-					ValueInputOption("USER_ENTERED").
-					Do()
-				if err != nil {
-					return c.Send("❌ Failed to save: " + err.Error())
-				}
-
-				delete(userStates, c.Sender().ID)
-				refreshData(strg)
-
-				return c.Send(fmt.Sprintf(`✅ Qo'shildi: %s, %s, %s, Nominal: %s`, state.Name, state.Phone, state.Summa, state.NominalSum))
+	// Handle export: overall totals XLSX
+	bot.Handle(&telebot.Btn{Unique: "export_totals"}, func(c telebot.Context) error {
+		refreshData(strg, false)
+
+		var totals report.Totals
+		for _, row := range strg.Rows() {
+			if len(row) < 6 {
+				continue
 			}
+			totals.Summa += parseFloat(row[2])
+			totals.Berdi += parseFloat(row[3])
+			totals.Qoldiq += parseFloat(row[4])
+			totals.NominalSum += parseFloat(row[5])
 		}
 
-		// EDIT FLOW (enter summa)
-		if state, ok := editStates[c.Sender().ID]; ok && state.Step == 3 {
-			summa := c.Text()
+		buf, err := report.TotalsXLSX(totals)
+		if err != nil {
+			return c.Send(t(c.Sender().ID, "export.failed", err.Error()))
+		}
 
-			parts := strings.SplitN(state.Name, "|", 2)
-			if len(parts) != 2 {
-				return c.Send("❌ Internal error: invalid state")
-			}
-			targetName, targetPhone := parts[0], parts[1]
-
-			var rowIndex int
-			for i, row := range strg.Data {
-				if len(row) > 1 &&
-					strings.EqualFold(fmt.Sprintf("%v", row[0]), targetName) &&
-					strings.EqualFold(fmt.Sprintf("%v", row[1]), targetPhone) {
-					rowIndex = i + 2
-					break
-				}
-			}
+		return c.Send(&telebot.Document{
+			File:     telebot.FromReader(buf),
+			FileName: "totals.xlsx",
+		})
+	})
 
-			var col int
-			switch state.Year {
-			case "2025":
-				col = 7 + monthsMap[state.Month]
-			case "2026":
-				col = 19 + monthsMap[state.Month]
-			}
+	// Handle /history <name> command
+	bot.Handle("/history", func(c telebot.Context) error {
+		name := strings.TrimSpace(c.Message().Payload)
+		if name == "" {
+			return c.Send(t(c.Sender().ID, "history.usage"))
+		}
 
-			cell := fmt.Sprintf("%s%d", string(rune('A'+col-1)), rowIndex)
+		entries, err := strg.History(name)
+		if err != nil {
+			return c.Send(t(c.Sender().ID, "history.failed", err.Error()))
+		}
+		if len(entries) == 0 {
+			return c.Send(t(c.Sender().ID, "history.not_found"))
+		}
+
+		var result strings.Builder
+		result.WriteString(t(c.Sender().ID, "history.header", html.EscapeString(name)))
+		for _, e := range entries {
+			line := t(c.Sender().ID, "history.entry",
+				e.Ts.Format("2006-01-02 15:04"), e.Username, e.UserID, e.Action, e.Cell, e.OldValue, e.NewValue)
+			result.WriteString(html.EscapeString(line))
+		}
+		result.WriteString("</pre>")
+
+		return c.Send(result.String(), telebot.ModeHTML)
+	}, RequireRole(storage.RoleEditor))
+
+	// Handle /grant <user> <role>: owner-only, mutates the ACL at runtime.
+	bot.Handle("/grant", func(c telebot.Context) error {
+		if !isOwner(c) {
+			logDenied(c, c.Text())
+			return c.Send(t(c.Sender().ID, "auth.denied"))
+		}
+
+		parts := strings.Fields(c.Message().Payload)
+		if len(parts) != 2 {
+			return c.Send(t(c.Sender().ID, "grant.usage"))
+		}
 
-			vr := &sheets.ValueRange{Values: [][]any{{summa}}}
-			_, err := srv.Spreadsheets.Values.Update(spreadsheetID, sheetName+"!"+cell, vr).
-				ValueInputOption("USER_ENTERED").Do()
-			if err != nil {
-				return c.Send("❌ Failed to update: " + err.Error())
+		user := strings.ToLower(strings.TrimPrefix(parts[0], "@"))
+		role := storage.Role(strings.ToLower(parts[1]))
+		if !role.Valid() {
+			return c.Send(t(c.Sender().ID, "grant.invalid_role"))
+		}
+
+		if err := strg.SetRole("user:"+user, role); err != nil {
+			return c.Send(t(c.Sender().ID, "grant.failed", err.Error()))
+		}
+
+		// roleFor prefers the ID-keyed role it mirrored, so update that too.
+		if id, ok := strg.IdentityID(user); ok {
+			if err := strg.SetRole(fmt.Sprintf("id:%d", id), role); err != nil {
+				return c.Send(t(c.Sender().ID, "grant.failed", err.Error()))
 			}
+		}
+
+		return c.Send(t(c.Sender().ID, "grant.success", user, string(role)))
+	})
 
-			delete(editStates, c.Sender().ID)
-			refreshData(strg)
-			return c.Send(fmt.Sprintf("✅ %s-yil %s oyi yangilandi — %s (%s) = %s", state.Year, state.Month, targetName, targetPhone, summa))
+	// Handle /revoke <user>: owner-only.
+	bot.Handle("/revoke", func(c telebot.Context) error {
+		if !isOwner(c) {
+			logDenied(c, c.Text())
+			return c.Send(t(c.Sender().ID, "auth.denied"))
 		}
 
-		return nil
+		user := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.Message().Payload), "@"))
+		if user == "" {
+			return c.Send(t(c.Sender().ID, "revoke.usage"))
+		}
+
+		if err := strg.DeleteRole("user:" + user); err != nil {
+			return c.Send(t(c.Sender().ID, "revoke.failed", err.Error()))
+		}
+
+		// roleFor mirrors username-keyed grants onto the sender's numeric ID
+		// the first time they're seen, so the username key alone isn't
+		// enough to revoke access once that mirroring has happened.
+		if id, ok := strg.IdentityID(user); ok {
+			if err := strg.DeleteRole(fmt.Sprintf("id:%d", id)); err != nil {
+				return c.Send(t(c.Sender().ID, "revoke.failed", err.Error()))
+			}
+		}
+
+		return c.Send(t(c.Sender().ID, "revoke.success", user))
 	})
 
+	// Handle add + edit text flow: each message advances whatever FSM
+	// session the sender is currently in, if any.
+	bot.Handle(telebot.OnText, func(c telebot.Context) error {
+		if _, ok := sessions.Current(c.Sender().ID); !ok {
+			return nil
+		}
+		return advance(c, c.Text())
+	}, RequireRole(storage.RoleEditor))
+
 	// Handle select list flow
 	bot.Handle(&telebot.Btn{Unique: "select"}, func(c telebot.Context) error {
 		parts := strings.SplitN(c.Data(), "|", 2)
 		if len(parts) != 2 {
-			return c.Send("❌ Invalid selection")
+			return c.Send(t(c.Sender().ID, "select.invalid"))
 		}
 		selectedName, selectedPhone := parts[0], parts[1]
+		lang := userLang(c.Sender().ID, strg)
 
-		var result string
-		months := []string{"Yanvar", "Fevral", "Mart", "Aprel", "May", "Iyun", "Iyul", "Avgust", "Sentabr", "Oktabr", "Noyabr", "Dekabr"}
-
-		for _, row := range strg.Data {
-			if len(row) > 1 &&
-				strings.EqualFold(fmt.Sprintf("%v", row[0]), selectedName) &&
-				strings.EqualFold(fmt.Sprintf("%v", row[1]), selectedPhone) {
-
-				result = fmt.Sprintf("📌 Name: %v\n📱 Phone: %v\n💰 Summa: %v\n✅ Berdi: %v\n💸 Qoldiq: %v\n📊 Nominal Sum: %v\n🤑 Foyda: %v\n\n",
-					row[0], row[1],
-					formatMoney(parseFloat(row[2])),
-					formatMoney(parseFloatFromCell(row[3])),
-					formatMoney(parseFloatFromCell(row[4])),
-					formatMoney(parseFloat(row[5])),
-					formatMoney(parseFloat(fmt.Sprintf("%v", row[2]))-parseFloat(fmt.Sprintf("%v", row[5]))),
-				)
-
-				result += "📅 Payments:\n<pre>"
-
-				result += "--------------2025--------------\n"
-				for i, m := range months {
-					col := 6 + i
-					val := 0.0
-					if col < len(row) {
-						val = parseFloatFromCell(row[col])
-					}
-					line := fmt.Sprintf("📅 %-9s: %9s\n", m, formatMoney(val))
-					result += html.EscapeString(line)
-				}
-
-				result += "--------------2026--------------\n"
-				for i, m := range months {
-					col := 18 + i
-					val := 0.0
-					if col < len(row) {
-						val = parseFloatFromCell(row[col])
-					}
-					line := fmt.Sprintf("📅 %-9s: %9s\n", m, formatMoney(val))
-					result += html.EscapeString(line)
-				}
-
-				result += "</pre>"
-				break
-			}
+		row := findRow(selectedName, selectedPhone)
+		if row == nil {
+			return c.Send(i18n.T(lang, "select.not_found"))
 		}
 
-		if result == "" {
-			result = "Not found."
+		p := personFromRow(row)
+		// The chat view shows month names in the sender's language.
+		labels := i18n.Months(lang)
+		for i := range p.Payments {
+			p.Payments[i].Month = labels[monthsMap[p.Payments[i].Month]]
 		}
 
-		return c.Send(result, telebot.ModeHTML)
-	})
+		result := i18n.T(lang, "select.header",
+			row[0], row[1],
+			formatMoney(p.Summa), formatMoney(p.Berdi), formatMoney(p.Qoldiq), formatMoney(p.NominalSum), formatMoney(p.Foyda),
+		)
+		result += i18n.T(lang, "select.payments")
+		result += html.EscapeString(report.PersonTable(p, formatMoney))
+		result += "</pre>"
 
-	// Handle edit flow: select name
-	bot.Handle(&telebot.Btn{Unique: "edit_name"}, func(c telebot.Context) error {
-		editStates[c.Sender().ID] = &editState{Step: 1, Name: c.Data()}
 		menu := &telebot.ReplyMarkup{}
-		years := []string{"2025", "2026"}
-		var buttons []telebot.Btn
-		for _, y := range years {
-			btn := menu.Data(y, "edit_year", y)
-			buttons = append(buttons, btn)
-		}
-		menu.Inline(buttons)
-		return c.Send("📅 Select year:", menu)
+		menu.Inline(menu.Row(menu.Data(t(c.Sender().ID, "export.button"), "export_person", selectedName+"|"+selectedPhone)))
+
+		return c.Send(result, telebot.ModeHTML, menu)
 	})
 
-	// Handle edit flow: select year
-	bot.Handle(&telebot.Btn{Unique: "edit_year"}, func(c telebot.Context) error {
-		state := editStates[c.Sender().ID]
-		state.Year = c.Data()
-		state.Step = 2
+	// Handle export: per-person XLSX statement
+	bot.Handle(&telebot.Btn{Unique: "export_person"}, func(c telebot.Context) error {
+		parts := strings.SplitN(c.Data(), "|", 2)
+		if len(parts) != 2 {
+			return c.Send(t(c.Sender().ID, "select.invalid"))
+		}
 
-		menu := &telebot.ReplyMarkup{}
-		monthsOrder := []string{"Yanvar", "Fevral", "Mart", "Aprel", "May", "Iyun", "Iyul", "Avgust", "Sentabr", "Oktabr", "Noyabr", "Dekabr"}
+		row := findRow(parts[0], parts[1])
+		if row == nil {
+			return c.Send(t(c.Sender().ID, "select.not_found"))
+		}
 
-		var rows []telebot.Row
-		for i := 0; i < len(monthsOrder); i += 3 {
-			var btns []telebot.Btn
-			for j := i; j < i+3 && j < len(monthsOrder); j++ {
-				btns = append(btns, menu.Data(monthsOrder[j], "edit_month", monthsOrder[j]))
-			}
-			rows = append(rows, menu.Row(btns...))
+		buf, err := report.PersonXLSX(personFromRow(row))
+		if err != nil {
+			return c.Send(t(c.Sender().ID, "export.failed", err.Error()))
 		}
-		menu.Inline(rows...)
-		return c.Send("📅 Oyni tanlang:", menu)
+
+		return c.Send(&telebot.Document{
+			File:     telebot.FromReader(buf),
+			FileName: fmt.Sprintf("%s_%s.xlsx", parts[0], parts[1]),
+		})
 	})
 
+	// Handle edit flow: select name
+	bot.Handle(&telebot.Btn{Unique: "edit_name"}, func(c telebot.Context) error {
+		sessions.Enter(c.Sender().ID, &editPersonState{})
+		return advance(c, c.Data())
+	}, RequireRole(storage.RoleEditor))
+
+	// Handle edit flow: select year
+	bot.Handle(&telebot.Btn{Unique: "edit_year"}, func(c telebot.Context) error {
+		return advance(c, c.Data())
+	}, RequireRole(storage.RoleEditor))
+
 	// Handle edit flow: select month
 	bot.Handle(&telebot.Btn{Unique: "edit_month"}, func(c telebot.Context) error {
-		state := editStates[c.Sender().ID]
-		state.Month = c.Data()
-		state.Step = 3
-		return c.Send(fmt.Sprintf("💰 Enter summa for %s %s (%s):", state.Month, state.Year, state.Name))
+		return advance(c, c.Data())
+	}, RequireRole(storage.RoleEditor))
+
+	// Handle /lang: language selection
+	bot.Handle(&telebot.Btn{Unique: "set_lang"}, func(c telebot.Context) error {
+		lang := c.Data()
+		if err := strg.SetLang(c.Sender().ID, lang); err != nil {
+			logger.Error("unable to save language preference", "error", err)
+		}
+		return c.Send(i18n.T(lang, "lang.set", i18n.Names[lang]))
 	})
 
-	log.Println("Bot started...")
+	logger.Info("bot started")
 	bot.Start()
 }
 
-func refreshData(data *Storage) {
-	data.mu.Lock()
-	defer data.mu.Unlock()
+// refreshData performs an incremental sync: it only re-fetches the sheet
+// once the store's TTL has expired (or force is set, e.g. right after a
+// write), otherwise reads are served straight from the SQLite mirror.
+func refreshData(data *storage.Store, force bool) {
+	if !force && !data.NeedsRefresh() {
+		return
+	}
 
 	resp, err := srv.Spreadsheets.Values.Get(spreadsheetID, readRange).Do()
 	if err != nil {
-		log.Fatalf("Unable to retrieve data from sheet: %v", err)
+		logger.Error("unable to retrieve data from sheet", "error", err)
+		return
+	}
+
+	if err := data.ReplaceAll(resp.Values); err != nil {
+		logger.Error("unable to update local mirror", "error", err)
+	}
+}
+
+// seedACL grants the roles listed in cfg to whichever of them don't
+// already have a persisted role, so a fresh database picks up the
+// env-configured tiers but a runtime /grant or /revoke survives restarts.
+func seedACL(strg *storage.Store, cfg Config) {
+	seed := func(users []string, role storage.Role) {
+		for _, u := range users {
+			key := "user:" + strings.ToLower(u)
+			if _, ok := strg.Role(key); ok {
+				continue
+			}
+			if err := strg.SetRole(key, role); err != nil {
+				logger.Error("unable to seed ACL role", "user", u, "error", err)
+			}
+		}
+	}
+
+	seed(cfg.ViewerUsers, storage.RoleViewer)
+	seed(cfg.AllowedUsers, storage.RoleViewer)
+	seed(cfg.EditorUsers, storage.RoleEditor)
+	seed(cfg.AdminUsers, storage.RoleAdmin)
+	if cfg.OwnerUser != "" {
+		seed([]string{cfg.OwnerUser}, storage.RoleAdmin)
+	}
+}
+
+// roleKeys returns the two ACL keys a sender can be found under: their
+// numeric Telegram ID (stable) and their lowercase username (can change).
+func roleKeys(c telebot.Context) (idKey, userKey string) {
+	return fmt.Sprintf("id:%d", c.Sender().ID), "user:" + strings.ToLower(c.Sender().Username)
+}
+
+// roleFor looks up the sender's ACL role, preferring the ID-keyed entry.
+// A role found only under the username key is mirrored onto the ID key,
+// so the grant keeps working even if the user later renames themselves.
+func roleFor(c telebot.Context) (storage.Role, bool) {
+	idKey, userKey := roleKeys(c)
+	if role, ok := strg.Role(idKey); ok {
+		return role, true
 	}
+	if role, ok := strg.Role(userKey); ok {
+		if err := strg.SetRole(idKey, role); err != nil {
+			logger.Error("unable to link ACL id", "sender_id", c.Sender().ID, "error", err)
+		}
+		if err := strg.SetIdentity(strings.ToLower(c.Sender().Username), c.Sender().ID); err != nil {
+			logger.Error("unable to record ACL identity", "sender_id", c.Sender().ID, "error", err)
+		}
+		return role, true
+	}
+	return "", false
+}
 
-	data.Data = resp.Values
+// isOwner reports whether the sender is the configured OWNER_USER. Owner
+// status is separate from the admin role: it's what gates /grant and
+// /revoke, rather than bot feature access. Like roleFor, it prefers a
+// numeric-ID match over the username so the owner doesn't lock themselves
+// out of /grant/revoke by renaming — the ID is recorded the first time the
+// owner is seen under their configured username.
+func isOwner(c telebot.Context) bool {
+	if ownerUser == "" {
+		return false
+	}
+	if strings.ToLower(c.Sender().Username) == ownerUser {
+		if err := strg.SetIdentity(ownerUser, c.Sender().ID); err != nil {
+			logger.Error("unable to record owner identity", "sender_id", c.Sender().ID, "error", err)
+		}
+		return true
+	}
+	id, ok := strg.IdentityID(ownerUser)
+	return ok && c.Sender().ID == id
+}
+
+// logDenied records a denied attempt (auth failure or insufficient role)
+// in the audit log, so an admin can see who tried what.
+func logDenied(c telebot.Context, command string) {
+	if err := strg.LogEdit(storage.AuditEntry{
+		UserID:   c.Sender().ID,
+		Username: c.Sender().Username,
+		Action:   "denied",
+		Cell:     command,
+	}); err != nil {
+		logger.Error("unable to log denied attempt", "error", err)
+	}
 }
 
+// authMiddleware lets through any sender with an ACL role (i.e. at least
+// viewer) and denies everyone else.
 func authMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
 	return func(c telebot.Context) error {
-		u := strings.ToLower(c.Sender().Username)
-		if u == "" || !allowedUsers[u] {
-			return c.Send("❌ You are not allowed to use this bot.")
+		if _, ok := roleFor(c); !ok {
+			logDenied(c, c.Text())
+			return c.Send(t(c.Sender().ID, "auth.denied"))
 		}
 		return next(c)
 	}
 }
 
+// RequireRole returns middleware that only admits senders whose ACL role
+// meets min (e.g. /add and /edit require RoleEditor). It must run after
+// authMiddleware, which already confirmed the sender has some role.
+func RequireRole(min storage.Role) func(telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(next telebot.HandlerFunc) telebot.HandlerFunc {
+		return func(c telebot.Context) error {
+			role, ok := roleFor(c)
+			if !ok || !role.AtLeast(min) {
+				logDenied(c, c.Text())
+				return c.Send(t(c.Sender().ID, "auth.denied"))
+			}
+			return next(c)
+		}
+	}
+}
+
+// recoverMiddleware catches a panic anywhere further down the chain so one
+// bad update can't crash the whole bot. The sender gets a short apology
+// with a reference id; the full stack trace goes to the structured log
+// under that same id so it can be looked up.
+func recoverMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(c telebot.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				refID := strconv.FormatInt(time.Now().UnixNano(), 36)
+				logger.Error("panic recovered",
+					"reference_id", refID,
+					"sender_id", c.Sender().ID,
+					"username", c.Sender().Username,
+					"stack", goerrors.Wrap(r, 2).ErrorStack(),
+				)
+				err = c.Send(fmt.Sprintf("internal error, reference id %s", refID))
+			}
+		}()
+		return next(c)
+	}
+}
+
+// logMiddleware records sender, chat, command and latency for every
+// update that reaches the bot, regardless of how the handler resolves it.
+func logMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		start := time.Now()
+		defer func() {
+			logger.Info("update",
+				"sender_id", c.Sender().ID,
+				"username", c.Sender().Username,
+				"chat_id", c.Chat().ID,
+				"command", c.Text(),
+				"latency_ms", time.Since(start).Milliseconds(),
+			)
+		}()
+		return next(c)
+	}
+}
+
+// cancelMiddleware intercepts /cancel before it reaches any handler and
+// ends whatever FSM flow the sender is in, so a stuck add/edit session
+// never needs more than this one command to escape.
+func cancelMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		if c.Text() != "/cancel" {
+			return next(c)
+		}
+		if sessions.Cancel(c.Sender().ID) {
+			return c.Send(t(c.Sender().ID, "cancel.success"))
+		}
+		return c.Send(t(c.Sender().ID, "cancel.none"))
+	}
+}
+
+// advance feeds input into the sender's current FSM state, sends every
+// reply it produces, and moves the session to the returned next state
+// (ending the flow if it returns nil).
+func advance(c telebot.Context, input string) error {
+	state, ok := sessions.Current(c.Sender().ID)
+	if !ok {
+		return nil
+	}
+
+	// Every FSM session is an add/edit flow, so every step writes to the
+	// sheet eventually. Re-check the role here, not just at session entry,
+	// so a sender demoted mid-flow can't finish the write via a stale
+	// keyboard or a late message.
+	if role, ok := roleFor(c); !ok || !role.AtLeast(storage.RoleEditor) {
+		logDenied(c, input)
+		sessions.Cancel(c.Sender().ID)
+		return c.Send(t(c.Sender().ID, "auth.denied"))
+	}
+
+	next, replies, err := state.Handle(fsm.Input{
+		UserID:   c.Sender().ID,
+		Username: c.Sender().Username,
+		Text:     input,
+	})
+	if err != nil {
+		logger.Error("unable to advance FSM session", "sender_id", c.Sender().ID, "error", err)
+		return c.Send(t(c.Sender().ID, "edit.invalid_state"))
+	}
+
+	sessions.Transition(c.Sender().ID, next)
+
+	for _, r := range replies {
+		if r.Markup != nil && r.Mode != "" {
+			if err := c.Send(r.Text, r.Markup, r.Mode); err != nil {
+				return err
+			}
+		} else if r.Markup != nil {
+			if err := c.Send(r.Text, r.Markup); err != nil {
+				return err
+			}
+		} else if r.Mode != "" {
+			if err := c.Send(r.Text, r.Mode); err != nil {
+				return err
+			}
+		} else {
+			if err := c.Send(r.Text); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// userLang returns the sender's stored language preference, or
+// i18n.Default if they haven't picked one via /lang yet.
+func userLang(userID int64, strg *storage.Store) string {
+	if lang, ok := strg.Lang(userID); ok {
+		return lang
+	}
+	return i18n.Default
+}
+
+// t renders message id for userID's chosen language. It relies on the
+// package-level strg being set before any handler runs.
+func t(userID int64, id string, args ...any) string {
+	return i18n.T(userLang(userID, strg), id, args...)
+}
+
+// personFromRow builds a report.Person from a sheet row, using canonical
+// (Uzbek) month names — the export formats are data, not chat text, so
+// they don't need per-user localization.
+func personFromRow(row []any) report.Person {
+	summa := parseFloat(row[2])
+	berdi := parseFloatFromCell(row[3])
+	qoldiq := parseFloatFromCell(row[4])
+	nominalSum := parseFloat(row[5])
+
+	p := report.Person{
+		Name:       fmt.Sprintf("%v", row[0]),
+		Phone:      fmt.Sprintf("%v", row[1]),
+		Summa:      summa,
+		Berdi:      berdi,
+		Qoldiq:     qoldiq,
+		NominalSum: nominalSum,
+		Foyda:      summa - nominalSum,
+	}
+
+	for _, year := range yearLayout.sortedYears() {
+		for i, m := range canonicalMonths {
+			col := yearLayout[year] - 1 + i
+			if col >= len(row) {
+				continue
+			}
+			p.Payments = append(p.Payments, report.MonthPayment{
+				Year: year, Month: m, Amount: parseFloatFromCell(row[col]),
+			})
+		}
+	}
+
+	return p
+}
+
+// findRow returns the sheet row matching name|phone, or nil.
+func findRow(name, phone string) []any {
+	for _, row := range strg.Rows() {
+		if len(row) > 1 &&
+			strings.EqualFold(fmt.Sprintf("%v", row[0]), name) &&
+			strings.EqualFold(fmt.Sprintf("%v", row[1]), phone) {
+			return row
+		}
+	}
+	return nil
+}
+
+// cellAt returns the stringified value of row[col], or "" if out of range.
+func cellAt(row []any, col int) string {
+	if col < 0 || col >= len(row) {
+		return ""
+	}
+	return fmt.Sprintf("%v", row[col])
+}
+
 func parseFloatFromCell(v any) float64 {
 	if v == nil {
 		return 0
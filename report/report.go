@@ -0,0 +1,119 @@
+// Package report renders a person's payment history as an aligned table,
+// and builds downloadable XLSX documents of that statement and of the
+// sheet-wide totals for the bot's "📥 Export" button.
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/tealeg/xlsx"
+)
+
+// MonthPayment is one row of a person's payment history.
+type MonthPayment struct {
+	Year   string
+	Month  string
+	Amount float64
+}
+
+// Person holds everything needed to render a per-person statement.
+type Person struct {
+	Name       string
+	Phone      string
+	Summa      float64
+	Berdi      float64
+	Qoldiq     float64
+	NominalSum float64
+	Foyda      float64
+	Payments   []MonthPayment
+}
+
+// PersonTable renders p's payment history as an aligned table (year, month,
+// amount, running total, percent of the nominal sum paid off so far),
+// using text/tabwriter so columns line up regardless of name/amount width.
+func PersonTable(p Person, formatMoney func(float64) string) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 2, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Yil\tOy\tSumma\tJami\t%%\n")
+
+	var running float64
+	for _, pay := range p.Payments {
+		running += pay.Amount
+		percent := 0.0
+		if p.NominalSum != 0 {
+			percent = running / p.NominalSum * 100
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.1f%%\n",
+			pay.Year, pay.Month, formatMoney(pay.Amount), formatMoney(running), percent)
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+// PersonXLSX renders p's payment history as a single-sheet XLSX workbook.
+func PersonXLSX(p Person) (*bytes.Buffer, error) {
+	wb := xlsx.NewFile()
+	sheet, err := wb.AddSheet("Statement")
+	if err != nil {
+		return nil, fmt.Errorf("report: add sheet: %w", err)
+	}
+
+	addRow(sheet, "Name", p.Name)
+	addRow(sheet, "Phone", p.Phone)
+	addRow(sheet, "Summa", fmt.Sprintf("%.2f", p.Summa))
+	addRow(sheet, "Berdi", fmt.Sprintf("%.2f", p.Berdi))
+	addRow(sheet, "Qoldiq", fmt.Sprintf("%.2f", p.Qoldiq))
+	addRow(sheet, "Nominal Sum", fmt.Sprintf("%.2f", p.NominalSum))
+	addRow(sheet, "Foyda", fmt.Sprintf("%.2f", p.Foyda))
+
+	addRow(sheet, "", "")
+	addRow(sheet, "Year", "Month", "Amount")
+	for _, pay := range p.Payments {
+		addRow(sheet, pay.Year, pay.Month, fmt.Sprintf("%.2f", pay.Amount))
+	}
+
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		return nil, fmt.Errorf("report: write xlsx: %w", err)
+	}
+	return &buf, nil
+}
+
+// Totals holds the figures shown by /totals.
+type Totals struct {
+	Summa      float64
+	Berdi      float64
+	Qoldiq     float64
+	NominalSum float64
+}
+
+// TotalsXLSX renders t as a single-sheet XLSX workbook.
+func TotalsXLSX(t Totals) (*bytes.Buffer, error) {
+	wb := xlsx.NewFile()
+	sheet, err := wb.AddSheet("Totals")
+	if err != nil {
+		return nil, fmt.Errorf("report: add sheet: %w", err)
+	}
+
+	addRow(sheet, "Summa", fmt.Sprintf("%.2f", t.Summa))
+	addRow(sheet, "Berdi", fmt.Sprintf("%.2f", t.Berdi))
+	addRow(sheet, "Qoldiq", fmt.Sprintf("%.2f", t.Qoldiq))
+	addRow(sheet, "Nominal Sum", fmt.Sprintf("%.2f", t.NominalSum))
+
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		return nil, fmt.Errorf("report: write xlsx: %w", err)
+	}
+	return &buf, nil
+}
+
+func addRow(sheet *xlsx.Sheet, cells ...string) {
+	row := sheet.AddRow()
+	for _, c := range cells {
+		row.AddCell().Value = c
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gopkg.in/telebot.v3"
+
+	"google.golang.org/api/sheets/v4"
+
+	"github.com/Hot-One/variant-tg-bot/fsm"
+	"github.com/Hot-One/variant-tg-bot/i18n"
+	"github.com/Hot-One/variant-tg-bot/storage"
+)
+
+// validationReply builds the "same state again, with an explanation"
+// response NonEmpty/Numeric failures produce.
+func validationReply(self fsm.State, userID int64, err error) (fsm.State, []fsm.Reply, error) {
+	key := "validate.empty"
+	if err == fsm.ErrNotNumeric {
+		key = "validate.numeric"
+	}
+	return self, []fsm.Reply{{Text: t(userID, key)}}, nil
+}
+
+// --- add-person flow: Name -> Phone -> Summa -> NominalSum ---
+
+type addNameState struct{}
+
+func (s *addNameState) Handle(in fsm.Input) (fsm.State, []fsm.Reply, error) {
+	if err := fsm.NonEmpty(in.Text); err != nil {
+		return validationReply(s, in.UserID, err)
+	}
+	next := &addPhoneState{Name: in.Text}
+	return next, []fsm.Reply{{Text: t(in.UserID, "add.prompt_phone")}}, nil
+}
+
+type addPhoneState struct{ Name string }
+
+func (s *addPhoneState) Handle(in fsm.Input) (fsm.State, []fsm.Reply, error) {
+	if err := fsm.NonEmpty(in.Text); err != nil {
+		return validationReply(s, in.UserID, err)
+	}
+	next := &addSummaState{Name: s.Name, Phone: formatPhoneModel(in.Text)}
+	return next, []fsm.Reply{{Text: t(in.UserID, "add.prompt_summa")}}, nil
+}
+
+type addSummaState struct{ Name, Phone string }
+
+func (s *addSummaState) Handle(in fsm.Input) (fsm.State, []fsm.Reply, error) {
+	if err := fsm.Numeric(in.Text); err != nil {
+		return validationReply(s, in.UserID, err)
+	}
+	next := &addNominalState{Name: s.Name, Phone: s.Phone, Summa: in.Text}
+	return next, []fsm.Reply{{Text: t(in.UserID, "add.prompt_nominal")}}, nil
+}
+
+type addNominalState struct{ Name, Phone, Summa string }
+
+func (s *addNominalState) Handle(in fsm.Input) (fsm.State, []fsm.Reply, error) {
+	if err := fsm.Numeric(in.Text); err != nil {
+		return validationReply(s, in.UserID, err)
+	}
+	nominalSum := in.Text
+
+	rowIndex := len(strg.Rows()) + 2
+	row := []any{
+		s.Name,
+		s.Phone,
+		s.Summa,
+		fmt.Sprintf("=СУММ(G%d:AZ%d)", rowIndex, rowIndex),
+		fmt.Sprintf("=C%d-D%d", rowIndex, rowIndex),
+		nominalSum,
+	}
+
+	vr := &sheets.ValueRange{Values: [][]any{row}}
+	if _, err := srv.Spreadsheets.Values.
+		Append(spreadsheetID, sheetName+"!A3", vr).
+		ValueInputOption("USER_ENTERED").
+		Do(); err != nil {
+		return nil, []fsm.Reply{{Text: t(in.UserID, "add.failed", err.Error())}}, nil
+	}
+
+	if err := strg.LogEdit(storage.AuditEntry{
+		UserID:      in.UserID,
+		Username:    in.Username,
+		Action:      "add",
+		TargetName:  s.Name,
+		TargetPhone: s.Phone,
+		NewValue:    s.Summa,
+		Cell:        fmt.Sprintf("A%d", rowIndex),
+	}); err != nil {
+		log.Printf("Unable to log audit entry: %v", err)
+	}
+
+	refreshData(strg, true)
+
+	return nil, []fsm.Reply{{Text: t(in.UserID, "add.success", s.Name, s.Phone, s.Summa, nominalSum)}}, nil
+}
+
+// --- edit flow: Person -> Year -> Month -> Summa ---
+
+type editPersonState struct{}
+
+func (s *editPersonState) Handle(in fsm.Input) (fsm.State, []fsm.Reply, error) {
+	parts := strings.SplitN(in.Text, "|", 2)
+	if len(parts) != 2 {
+		return nil, []fsm.Reply{{Text: t(in.UserID, "edit.invalid_state")}}, nil
+	}
+	next := &editYearState{Name: parts[0], Phone: parts[1]}
+
+	menu := &telebot.ReplyMarkup{}
+	var buttons []telebot.Btn
+	for _, y := range yearLayout.sortedYears() {
+		buttons = append(buttons, menu.Data(y, "edit_year", y))
+	}
+	menu.Inline(buttons)
+
+	return next, []fsm.Reply{{Text: t(in.UserID, "edit.choose_year"), Markup: menu}}, nil
+}
+
+type editYearState struct{ Name, Phone string }
+
+func (s *editYearState) Handle(in fsm.Input) (fsm.State, []fsm.Reply, error) {
+	next := &editMonthState{Name: s.Name, Phone: s.Phone, Year: in.Text}
+
+	labels := i18n.Months(userLang(in.UserID, strg))
+	menu := &telebot.ReplyMarkup{}
+	var rows []telebot.Row
+	for i := 0; i < len(canonicalMonths); i += 3 {
+		var btns []telebot.Btn
+		for j := i; j < i+3 && j < len(canonicalMonths); j++ {
+			btns = append(btns, menu.Data(labels[j], "edit_month", canonicalMonths[j]))
+		}
+		rows = append(rows, menu.Row(btns...))
+	}
+	menu.Inline(rows...)
+
+	return next, []fsm.Reply{{Text: t(in.UserID, "edit.choose_month"), Markup: menu}}, nil
+}
+
+type editMonthState struct{ Name, Phone, Year string }
+
+func (s *editMonthState) Handle(in fsm.Input) (fsm.State, []fsm.Reply, error) {
+	next := &editSummaState{Name: s.Name, Phone: s.Phone, Year: s.Year, Month: in.Text}
+	monthLabel := i18n.Months(userLang(in.UserID, strg))[monthsMap[in.Text]]
+	return next, []fsm.Reply{{Text: t(in.UserID, "edit.prompt_summa", monthLabel, s.Year, s.Name)}}, nil
+}
+
+type editSummaState struct{ Name, Phone, Year, Month string }
+
+func (s *editSummaState) Handle(in fsm.Input) (fsm.State, []fsm.Reply, error) {
+	if err := fsm.Numeric(in.Text); err != nil {
+		return validationReply(s, in.UserID, err)
+	}
+	summa := in.Text
+
+	col := yearLayout[s.Year] + monthsMap[s.Month]
+
+	var rowIndex int
+	var oldValue string
+	for i, row := range strg.Rows() {
+		if len(row) > 1 &&
+			strings.EqualFold(fmt.Sprintf("%v", row[0]), s.Name) &&
+			strings.EqualFold(fmt.Sprintf("%v", row[1]), s.Phone) {
+			rowIndex = i + 2
+			oldValue = cellAt(row, col-1)
+			break
+		}
+	}
+
+	cell := fmt.Sprintf("%s%d", colIndexToA1(col), rowIndex)
+
+	vr := &sheets.ValueRange{Values: [][]any{{summa}}}
+	if _, err := srv.Spreadsheets.Values.Update(spreadsheetID, sheetName+"!"+cell, vr).
+		ValueInputOption("USER_ENTERED").Do(); err != nil {
+		return nil, []fsm.Reply{{Text: t(in.UserID, "edit.failed", err.Error())}}, nil
+	}
+
+	if err := strg.LogEdit(storage.AuditEntry{
+		UserID:      in.UserID,
+		Username:    in.Username,
+		Action:      "edit",
+		TargetName:  s.Name,
+		TargetPhone: s.Phone,
+		OldValue:    oldValue,
+		NewValue:    summa,
+		Cell:        cell,
+	}); err != nil {
+		log.Printf("Unable to log audit entry: %v", err)
+	}
+
+	refreshData(strg, true)
+
+	monthLabel := i18n.Months(userLang(in.UserID, strg))[monthsMap[s.Month]]
+	return nil, []fsm.Reply{{Text: t(in.UserID, "edit.success", s.Year, monthLabel, s.Name, s.Phone, summa)}}, nil
+}
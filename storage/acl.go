@@ -0,0 +1,94 @@
+package storage
+
+import "fmt"
+
+// Role is an ACL tier. Roles are totally ordered: Viewer < Editor < Admin.
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// rank orders the known roles for AtLeast comparisons. An unrecognized
+// role ranks below all of them.
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 0
+	case RoleEditor:
+		return 1
+	case RoleAdmin:
+		return 2
+	default:
+		return -1
+	}
+}
+
+// AtLeast reports whether r meets or exceeds min.
+func (r Role) AtLeast(min Role) bool {
+	return r.rank() >= 0 && r.rank() >= min.rank()
+}
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	return r.rank() >= 0
+}
+
+// SetRole grants role to key, persisting it. key is either "id:<telegram
+// id>" or "user:<lowercase username>" — callers key by both so a grant
+// made against a username still applies once that user's numeric ID is
+// known, and survives the user later changing their username.
+func (s *Store) SetRole(key string, role Role) error {
+	_, err := s.db.Exec(
+		`INSERT INTO acl(key, role) VALUES (?, ?)
+		 ON CONFLICT(key) DO UPDATE SET role = excluded.role`,
+		key, string(role),
+	)
+	if err != nil {
+		return fmt.Errorf("storage: set role: %w", err)
+	}
+	return nil
+}
+
+// DeleteRole removes any role granted to key.
+func (s *Store) DeleteRole(key string) error {
+	if _, err := s.db.Exec(`DELETE FROM acl WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("storage: delete role: %w", err)
+	}
+	return nil
+}
+
+// Role returns the role granted to key, if any.
+func (s *Store) Role(key string) (Role, bool) {
+	var role string
+	if err := s.db.QueryRow(`SELECT role FROM acl WHERE key = ?`, key).Scan(&role); err != nil {
+		return "", false
+	}
+	return Role(role), true
+}
+
+// SetIdentity records that username resolved to telegramID, so a later
+// /revoke of the username can also find and clear the ID-keyed role that
+// roleFor mirrored onto it.
+func (s *Store) SetIdentity(username string, telegramID int64) error {
+	_, err := s.db.Exec(
+		`INSERT INTO acl_identities(username, telegram_id) VALUES (?, ?)
+		 ON CONFLICT(username) DO UPDATE SET telegram_id = excluded.telegram_id`,
+		username, telegramID,
+	)
+	if err != nil {
+		return fmt.Errorf("storage: set identity: %w", err)
+	}
+	return nil
+}
+
+// IdentityID returns the Telegram ID last seen for username, if any.
+func (s *Store) IdentityID(username string) (int64, bool) {
+	var id int64
+	if err := s.db.QueryRow(`SELECT telegram_id FROM acl_identities WHERE username = ?`, username).Scan(&id); err != nil {
+		return 0, false
+	}
+	return id, true
+}
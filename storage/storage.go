@@ -0,0 +1,266 @@
+// Package storage keeps a local SQLite mirror of the Google Sheet plus an
+// append-only audit log of every edit made through the bot, so reads don't
+// have to hit the Sheets API and admins can see who changed what.
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// AuditEntry is one row of the audit_log table.
+type AuditEntry struct {
+	ID          int64
+	UserID      int64
+	Username    string
+	Action      string
+	TargetName  string
+	TargetPhone string
+	OldValue    string
+	NewValue    string
+	Cell        string
+	Ts          time.Time
+}
+
+// Store is a SQLite-backed mirror of the sheet with a TTL-based refresh
+// policy: reads are served from the mirror, writes go to Sheets first and
+// are then recorded via LogEdit and applied here by a forced ReplaceAll.
+type Store struct {
+	mu       sync.RWMutex
+	db       *sql.DB
+	ttl      time.Duration
+	lastSync time.Time
+	rows     [][]any
+}
+
+// Open creates/migrates the SQLite database at path and returns a Store
+// with the given refresh TTL.
+func Open(path string, ttl time.Duration) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("storage: open %s: %w", path, err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("storage: migrate: %w", err)
+	}
+
+	s := &Store{db: db, ttl: ttl}
+	if err := s.loadRows(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("storage: load mirror: %w", err)
+	}
+
+	return s, nil
+}
+
+// loadRows populates s.rows and s.lastSync from the sheet_rows table so a
+// restart starts warm from the mirror instead of forcing an immediate
+// Sheets refetch.
+func (s *Store) loadRows() error {
+	rows, err := s.db.Query(`SELECT row_index, data, updated_at FROM sheet_rows ORDER BY row_index`)
+	if err != nil {
+		return fmt.Errorf("query sheet_rows: %w", err)
+	}
+	defer rows.Close()
+
+	var mirror [][]any
+	var lastSync time.Time
+	for rows.Next() {
+		var idx int
+		var data string
+		var updatedAt time.Time
+		if err := rows.Scan(&idx, &data, &updatedAt); err != nil {
+			return fmt.Errorf("scan sheet_rows: %w", err)
+		}
+		var row []any
+		if err := json.Unmarshal([]byte(data), &row); err != nil {
+			return fmt.Errorf("unmarshal row %d: %w", idx, err)
+		}
+		mirror = append(mirror, row)
+		if updatedAt.After(lastSync) {
+			lastSync = updatedAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate sheet_rows: %w", err)
+	}
+
+	s.rows = mirror
+	s.lastSync = lastSync
+	return nil
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS sheet_rows (
+	row_index  INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	phone      TEXT NOT NULL,
+	data       TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id      INTEGER NOT NULL,
+	username     TEXT NOT NULL,
+	action       TEXT NOT NULL,
+	target_name  TEXT NOT NULL,
+	target_phone TEXT NOT NULL,
+	old_value    TEXT NOT NULL,
+	new_value    TEXT NOT NULL,
+	cell         TEXT NOT NULL,
+	ts           TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_name);
+
+CREATE TABLE IF NOT EXISTS user_prefs (
+	user_id INTEGER PRIMARY KEY,
+	lang    TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS acl (
+	key  TEXT PRIMARY KEY,
+	role TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS acl_identities (
+	username    TEXT PRIMARY KEY,
+	telegram_id INTEGER NOT NULL
+);
+`
+
+// Close releases the underlying database handle.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// NeedsRefresh reports whether the mirror is empty or older than the TTL.
+func (s *Store) NeedsRefresh() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.rows) == 0 || time.Since(s.lastSync) > s.ttl
+}
+
+// Rows returns the current in-memory mirror (read path for /list, /edit,
+// /totals). Callers must not mutate the returned slice.
+func (s *Store) Rows() [][]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rows
+}
+
+// ReplaceAll overwrites the mirror with freshly-fetched sheet rows and
+// persists them, resetting the TTL clock. This is what an incremental sync
+// calls once the TTL has expired or right after a write.
+func (s *Store) ReplaceAll(rows [][]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("storage: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM sheet_rows"); err != nil {
+		return fmt.Errorf("storage: clear mirror: %w", err)
+	}
+
+	now := time.Now()
+	for i, row := range rows {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return fmt.Errorf("storage: marshal row %d: %w", i, err)
+		}
+		name, phone := cellString(row, 0), cellString(row, 1)
+		if _, err := tx.Exec(
+			`INSERT INTO sheet_rows(row_index, name, phone, data, updated_at) VALUES (?, ?, ?, ?, ?)`,
+			i, name, phone, data, now,
+		); err != nil {
+			return fmt.Errorf("storage: insert row %d: %w", i, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("storage: commit: %w", err)
+	}
+
+	s.rows = rows
+	s.lastSync = now
+	return nil
+}
+
+// LogEdit appends an entry to the audit log. Call it after a write to
+// Sheets has succeeded.
+func (s *Store) LogEdit(e AuditEntry) error {
+	_, err := s.db.Exec(
+		`INSERT INTO audit_log(user_id, username, action, target_name, target_phone, old_value, new_value, cell, ts)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.UserID, e.Username, e.Action, e.TargetName, e.TargetPhone, e.OldValue, e.NewValue, e.Cell, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("storage: log edit: %w", err)
+	}
+	return nil
+}
+
+// History returns the audit trail for a person, newest first.
+func (s *Store) History(name string) ([]AuditEntry, error) {
+	rows, err := s.db.Query(
+		`SELECT id, user_id, username, action, target_name, target_phone, old_value, new_value, cell, ts
+		 FROM audit_log WHERE target_name = ? COLLATE NOCASE ORDER BY ts DESC`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("storage: query history: %w", err)
+	}
+	defer rows.Close()
+
+	var out []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Username, &e.Action, &e.TargetName, &e.TargetPhone, &e.OldValue, &e.NewValue, &e.Cell, &e.Ts); err != nil {
+			return nil, fmt.Errorf("storage: scan history: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// SetLang persists the user's chosen language.
+func (s *Store) SetLang(userID int64, lang string) error {
+	_, err := s.db.Exec(
+		`INSERT INTO user_prefs(user_id, lang) VALUES (?, ?)
+		 ON CONFLICT(user_id) DO UPDATE SET lang = excluded.lang`,
+		userID, lang,
+	)
+	if err != nil {
+		return fmt.Errorf("storage: set lang: %w", err)
+	}
+	return nil
+}
+
+// Lang returns the user's chosen language and whether one was ever set.
+func (s *Store) Lang(userID int64) (string, bool) {
+	var lang string
+	err := s.db.QueryRow(`SELECT lang FROM user_prefs WHERE user_id = ?`, userID).Scan(&lang)
+	if err != nil {
+		return "", false
+	}
+	return lang, true
+}
+
+func cellString(row []any, col int) string {
+	if col >= len(row) {
+		return ""
+	}
+	return fmt.Sprintf("%v", row[col])
+}